@@ -0,0 +1,9 @@
+// Package crypto holds the key pair type shared by every noise signing
+// scheme, independent of which scheme actually generated it.
+package crypto
+
+// KeyPair holds a node's asymmetric signing keys.
+type KeyPair struct {
+	PublicKey  []byte
+	PrivateKey []byte
+}
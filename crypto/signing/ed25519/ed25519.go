@@ -0,0 +1,19 @@
+// Package ed25519 generates crypto.KeyPairs backed by the standard library's
+// ed25519 implementation.
+package ed25519
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+
+	"github.com/perlin-network/noise/crypto"
+)
+
+// RandomKeyPair generates a new random ed25519 key pair.
+func RandomKeyPair() *crypto.KeyPair {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	return &crypto.KeyPair{PublicKey: pub, PrivateKey: priv}
+}
@@ -0,0 +1,18 @@
+// Package messages holds the message types shared by the basic example and
+// by integration tests throughout the network packages.
+package messages
+
+import "encoding/gob"
+
+// BasicMessage is a plain text message.
+type BasicMessage struct {
+	Message string
+}
+
+func (m *BasicMessage) Reset()         { *m = BasicMessage{} }
+func (m *BasicMessage) String() string { return m.Message }
+func (m *BasicMessage) ProtoMessage()  {}
+
+func init() {
+	gob.Register(&BasicMessage{})
+}
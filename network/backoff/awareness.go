@@ -0,0 +1,59 @@
+package backoff
+
+import (
+	"sync"
+	"time"
+)
+
+// awareness tracks a cluster-wide health score for the local node, modeled
+// after HashiCorp memberlist's Lifeguard mechanism. A score of 0 means the
+// node is fully healthy; the score climbs towards max as the node observes
+// more failures (timed out dials, Receive errors) than successes, and every
+// delay computed from the current backoff curve is stretched proportionally
+// to the score before the node sleeps on it.
+type awareness struct {
+	mu    sync.Mutex
+	score int
+	max   int
+}
+
+// newAwareness creates an awareness tracker whose score is clamped to [0, max).
+// A max <= 0 disables clamping entirely and the score is always 0.
+func newAwareness(max int) *awareness {
+	return &awareness{max: max}
+}
+
+// ApplyDelta adjusts the score by delta, clamping the result to [0, max).
+func (a *awareness) ApplyDelta(delta int) {
+	if a.max <= 0 {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.score += delta
+	if a.score < 0 {
+		a.score = 0
+	} else if a.score >= a.max {
+		a.score = a.max - 1
+	}
+}
+
+// Score returns the current awareness score.
+func (a *awareness) Score() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.score
+}
+
+// Scale stretches d in proportion to the current score: a score of 0 leaves d
+// untouched, while a higher score multiplies it, slowing down retries across
+// every peer whenever the local node is observed to be unhealthy.
+func (a *awareness) Scale(d time.Duration) time.Duration {
+	a.mu.Lock()
+	score := a.score
+	a.mu.Unlock()
+
+	return d * time.Duration(score+1)
+}
@@ -0,0 +1,236 @@
+// Package backoff implements a network plugin that automatically redials
+// peers that disconnect, backing off between attempts according to a
+// pluggable Strategy (exponential by default) so a single flapping peer does
+// not turn into a reconnect storm.
+package backoff
+
+import (
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/perlin-network/noise/network"
+	"github.com/perlin-network/noise/network/broadcast"
+)
+
+const (
+	// initialDelay is the delay before the very first reconnect attempt.
+	initialDelay = 1 * time.Second
+
+	// defaultMinInterval is the base unit the exponential curve is built on.
+	defaultMinInterval = 1 * time.Second
+
+	// defaultMaxInterval caps how long the plugin will ever wait between
+	// reconnect attempts, regardless of how many attempts have failed.
+	defaultMaxInterval = 32 * time.Second
+
+	// defaultAwarenessMax is the number of discrete health levels tracked
+	// by the plugin's awareness score.
+	defaultAwarenessMax = 8
+
+	// defaultDialTimeoutDelta/defaultDialSuccessDelta are the awareness
+	// adjustments applied on a failed/successful dial respectively.
+	defaultDialTimeoutDelta = 1
+	defaultDialSuccessDelta = -1
+)
+
+// PluginConfig configures the reconnect behavior of Plugin. The zero value
+// is not usable; use DefaultPluginConfig to get sane defaults and override
+// only the fields that matter.
+type PluginConfig struct {
+	// Base is the starting delay of the Exponential Strategy that Startup
+	// builds when Strategy is nil. A Strategy set by the caller carries its
+	// own base and ignores this field.
+	Base time.Duration
+
+	// Cap bounds the delay of the Exponential Strategy that Startup builds
+	// when Strategy is nil, before it is scaled by awareness. A Strategy set
+	// by the caller carries its own cap and ignores this field.
+	Cap time.Duration
+
+	// Strategy computes the delay between redial attempts. It defaults to
+	// Exponential{Base, Cap} when left nil.
+	Strategy Strategy
+
+	// AwarenessMax is the number of discrete health levels the plugin's
+	// awareness score can occupy. Left nil, it defaults to
+	// defaultAwarenessMax; set to a pointer to 0 to disable awareness
+	// scaling entirely, since a plain 0 would be indistinguishable from
+	// "unset" and Startup would silently overwrite it with the default.
+	AwarenessMax *int
+
+	// DialTimeoutDelta is applied to the awareness score every time a dial
+	// to a peer times out or a Receive from network.MessageContext errors.
+	DialTimeoutDelta int
+
+	// DialSuccessDelta is applied to the awareness score every time a
+	// reconnect to a peer succeeds. It is expected to be negative.
+	DialSuccessDelta int
+
+	// Clock is consulted for every wait in the redial loop instead of the
+	// wall clock, so tests can drive reconnection with a virtual clock. It
+	// defaults to network.RealClock when left nil.
+	Clock network.Clock
+
+	// Broadcaster, if set, is paused for a peer as soon as it disconnects
+	// and resumed once the redial loop reconnects to it, so queued
+	// messages are held rather than silently dropped during the outage.
+	Broadcaster *broadcast.Broadcaster
+}
+
+// DefaultPluginConfig returns the configuration used when a Plugin is
+// registered without an explicit PluginConfig.
+func DefaultPluginConfig() PluginConfig {
+	max := defaultAwarenessMax
+	return PluginConfig{
+		Base:             defaultMinInterval,
+		Cap:              defaultMaxInterval,
+		AwarenessMax:     &max,
+		DialTimeoutDelta: defaultDialTimeoutDelta,
+		DialSuccessDelta: defaultDialSuccessDelta,
+	}
+}
+
+// Plugin redials peers that disconnect, backing off between attempts as
+// PluginConfig.Strategy dictates. The per-peer delay is additionally scaled by a cluster-wide
+// awareness score of the local node: dials that time out, or Receive errors
+// reported through network.MessageContext, push the score up and stretch
+// every other peer's delay, while successful reconnects push it back down.
+type Plugin struct {
+	*network.Plugin
+
+	config PluginConfig
+	net    *network.Network
+
+	awareness *awareness
+
+	mu      sync.Mutex
+	dialing map[string]chan struct{}
+}
+
+// Startup records the network instance so the reconnect loop can redial
+// through it, and lazily applies a default to any PluginConfig field left at
+// its zero value, independently of every other field.
+func (p *Plugin) Startup(net *network.Network) {
+	p.net = net
+	p.dialing = make(map[string]chan struct{})
+
+	defaults := DefaultPluginConfig()
+
+	if p.config.Base == 0 {
+		p.config.Base = defaults.Base
+	}
+	if p.config.Cap == 0 {
+		p.config.Cap = defaults.Cap
+	}
+	if p.config.AwarenessMax == nil {
+		p.config.AwarenessMax = defaults.AwarenessMax
+	}
+	if p.config.DialTimeoutDelta == 0 {
+		p.config.DialTimeoutDelta = defaults.DialTimeoutDelta
+	}
+	if p.config.DialSuccessDelta == 0 {
+		p.config.DialSuccessDelta = defaults.DialSuccessDelta
+	}
+	if p.config.Clock == nil {
+		p.config.Clock = network.RealClock
+	}
+	if p.config.Strategy == nil {
+		p.config.Strategy = &Exponential{Base: p.config.Base, Cap: p.config.Cap}
+	}
+	p.awareness = newAwareness(*p.config.AwarenessMax)
+}
+
+// Receive does not process messages itself; it exists only to satisfy
+// network.PluginInterface. Degraded-connection signal from message
+// processing reaches the awareness score through ObserveReceiveError below,
+// not through this hook.
+func (p *Plugin) Receive(ctx *network.MessageContext) error {
+	return nil
+}
+
+// ObserveReceiveError is called by the network dispatcher after any plugin's
+// Receive returns a non-nil error while processing a message from ctx. It
+// treats that failure as a sign of a degraded connection to ctx.Sender() and
+// applies the same positive awareness delta as a timed out dial, so a peer
+// that is reachable but misbehaving still slows down retries across the
+// node, not just one that can't be dialed at all.
+func (p *Plugin) ObserveReceiveError(ctx *network.MessageContext, err error) {
+	if err == nil {
+		return
+	}
+	p.awareness.ApplyDelta(p.config.DialTimeoutDelta)
+}
+
+// PeerDisconnect is called by the network whenever a peer connection drops.
+// It starts a background loop that redials the peer with a delay computed by
+// PluginConfig.Strategy, scaled by the current awareness score, until the dial
+// succeeds or the plugin's network is shut down.
+func (p *Plugin) PeerDisconnect(client *network.PeerClient) {
+	address := client.Address
+
+	p.mu.Lock()
+	if _, ok := p.dialing[address]; ok {
+		p.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	p.dialing[address] = stop
+	p.mu.Unlock()
+
+	if p.config.Broadcaster != nil {
+		p.config.Broadcaster.Pause(address)
+	}
+
+	go p.redial(address, stop)
+}
+
+// Health reports the local node's current awareness score: 0 is fully
+// healthy, and values approaching PluginConfig.AwarenessMax indicate the
+// node has recently seen more dial failures than successes. Applications
+// (and the discovery plugin) can use this to gate broadcasts while the node
+// is degraded.
+func (p *Plugin) Health() int {
+	if p.awareness == nil {
+		return 0
+	}
+	return p.awareness.Score()
+}
+
+// redial retries dialing address according to PluginConfig.Strategy until it
+// succeeds or stop is closed, applying a negative awareness delta on success
+// and a positive one on every timed out attempt.
+func (p *Plugin) redial(address string, stop chan struct{}) {
+	defer func() {
+		p.mu.Lock()
+		delete(p.dialing, address)
+		p.mu.Unlock()
+	}()
+
+	p.config.Strategy.Reset()
+
+	delay := initialDelay
+	for attempt := 0; ; attempt++ {
+		select {
+		case <-stop:
+			return
+		case <-p.net.Shutdown:
+			return
+		case <-p.config.Clock.After(p.awareness.Scale(delay)):
+		}
+
+		glog.Infof("backoff: attempting to reconnect to %s (attempt %d)", address, attempt)
+
+		if _, err := p.net.Client(address); err != nil {
+			p.awareness.ApplyDelta(p.config.DialTimeoutDelta)
+		} else {
+			p.awareness.ApplyDelta(p.config.DialSuccessDelta)
+			if p.config.Broadcaster != nil {
+				p.config.Broadcaster.Resume(address)
+			}
+			return
+		}
+
+		delay = p.config.Strategy.Next(attempt, delay)
+	}
+}
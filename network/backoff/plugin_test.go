@@ -1,27 +1,28 @@
 package backoff
 
 import (
-	"flag"
 	"fmt"
 	"os"
 	"testing"
 	"time"
 
-	"github.com/golang/glog"
+	"github.com/golang/protobuf/proto"
+
 	"github.com/perlin-network/noise/crypto"
 	"github.com/perlin-network/noise/crypto/signing/ed25519"
 	"github.com/perlin-network/noise/examples/basic/messages"
 	"github.com/perlin-network/noise/network"
+	"github.com/perlin-network/noise/network/broadcast"
 	"github.com/perlin-network/noise/network/builders"
 	"github.com/perlin-network/noise/network/discovery"
-	"github.com/pkg/errors"
+	"github.com/perlin-network/noise/network/simulations"
+	nwtesting "github.com/perlin-network/noise/network/testing"
 )
 
 const (
-	numNodes  = 2
-	protocol  = "tcp"
-	host      = "127.0.0.1"
-	startPort = 21200
+	numNodes = 2
+	protocol = "inproc"
+	host     = "127.0.0.1"
 )
 
 var keys = make(map[string]*crypto.KeyPair)
@@ -46,28 +47,36 @@ func (state *BasicPlugin) Receive(ctx *network.MessageContext) error {
 	return nil
 }
 
+// broadcastAndCheck broadcasts a message from Node 0 and builds an Exchange
+// expecting every other node to receive it, in any order.
 func broadcastAndCheck(nodes []*network.Network, plugins []*BasicPlugin) error {
-	// Broadcast out a message from Node 0.
 	expected := "This is a broadcasted message from Node 0."
-	nodes[0].Broadcast(&messages.BasicMessage{Message: expected})
 
-	// Check if message was received by other nodes.
+	exchange := nwtesting.Exchange{
+		Label:   "broadcast",
+		Timeout: 2 * time.Second,
+		Triggers: []nwtesting.Trigger{
+			{Node: nodes[0], Message: &messages.BasicMessage{Message: expected}},
+		},
+	}
+
 	for i := 1; i < len(nodes); i++ {
-		select {
-		case received := <-plugins[i].Mailbox:
-			if received.Message != expected {
-				return errors.Errorf("Expected message %s to be received by node %d but got %v\n", expected, i, received.Message)
-			}
-		case <-time.After(2 * time.Second):
-			return errors.Errorf("Timed out attempting to receive message from Node 0.\n")
-		}
+		i := i
+		exchange.Expects = append(exchange.Expects, nwtesting.Expect{
+			Label:   fmt.Sprintf("node %d receives broadcast", i),
+			Mailbox: plugins[i].Mailbox,
+			Match: func(msg interface{}) bool {
+				received, ok := msg.(*messages.BasicMessage)
+				return ok && received.Message == expected
+			},
+		})
 	}
 
-	return nil
+	return exchange.Run()
 }
 
-func newNode(i int, d bool, r bool) (*network.Network, *BasicPlugin, error) {
-	addr := network.FormatAddress(protocol, host, uint16(startPort+i))
+func newNode(sim *simulations.SimNetwork, clock *simulations.SimClock, strategy Strategy, i int, d bool, r bool) (*network.Network, *BasicPlugin, error) {
+	addr := network.FormatAddress(protocol, host, uint16(i))
 	if _, ok := keys[addr]; !ok {
 		keys[addr] = ed25519.RandomKeyPair()
 	}
@@ -80,16 +89,20 @@ func newNode(i int, d bool, r bool) (*network.Network, *BasicPlugin, error) {
 		builder.AddPlugin(new(discovery.Plugin))
 	}
 	if r {
-		builder.AddPlugin(new(Plugin))
+		config := DefaultPluginConfig()
+		config.Clock = clock
+		config.Strategy = strategy
+		builder.AddPlugin(&Plugin{config: config})
 	}
 
 	plugin := new(BasicPlugin)
 	builder.AddPlugin(plugin)
 
-	node, err := builder.Build()
+	node, err := sim.Adapter.NewNode(addr, builder)
 	if err != nil {
 		return nil, nil, err
 	}
+	sim.Add(node)
 
 	go node.Listen()
 
@@ -97,27 +110,66 @@ func newNode(i int, d bool, r bool) (*network.Network, *BasicPlugin, error) {
 
 	// Bootstrap to Node 0.
 	if d && i != 0 {
-		node.Bootstrap(network.FormatAddress(protocol, host, uint16(startPort)))
+		node.Bootstrap(network.FormatAddress(protocol, host, uint16(0)))
 	}
 
 	return node, plugin, nil
 }
 
-// TestPlugin tests the functionality of the exponential backoff as a plugin.
+// TestStartupAwarenessMax checks that AwarenessMax is defaulted when left
+// nil but left alone when explicitly set to a pointer to zero, so a caller
+// can actually disable awareness scaling rather than Startup silently
+// overwriting an explicit zero with the default.
+func TestStartupAwarenessMax(t *testing.T) {
+	disabled := 0
+	p := &Plugin{config: PluginConfig{AwarenessMax: &disabled}}
+	p.Startup(nil)
+
+	p.awareness.ApplyDelta(5)
+	if got := p.Health(); got != 0 {
+		t.Fatalf("expected awareness scaling disabled by explicit zero, got health %d", got)
+	}
+
+	defaulted := new(Plugin)
+	defaulted.Startup(nil)
+
+	if defaulted.config.AwarenessMax == nil || *defaulted.config.AwarenessMax != defaultAwarenessMax {
+		t.Fatalf("expected AwarenessMax to default to %d when left nil, got %v", defaultAwarenessMax, defaulted.config.AwarenessMax)
+	}
+}
+
+// TestPlugin tests the functionality of the backoff plugin under each
+// Strategy, driven entirely through an in-process SimNetwork and a virtual
+// SimClock so reconnection, partition healing, and jitter are exercised
+// deterministically: backoff windows are advanced on the virtual clock
+// rather than waited out, and only the broadcast checks and waitForPeer use
+// real timeouts.
 func TestPlugin(t *testing.T) {
 	t.Parallel()
-	if testing.Short() {
-		t.Skip("skipping backoff plugin test in short mode")
+
+	strategies := map[string]Strategy{
+		"Exponential":        &Exponential{Base: defaultMinInterval, Cap: defaultMaxInterval},
+		"FullJitter":         &FullJitter{Base: defaultMinInterval, Cap: defaultMaxInterval},
+		"DecorrelatedJitter": &DecorrelatedJitter{Base: defaultMinInterval, Cap: defaultMaxInterval},
 	}
 
-	flag.Set("logtostderr", "true")
-	flag.Parse()
+	for name, strategy := range strategies {
+		strategy := strategy
+		t.Run(name, func(t *testing.T) {
+			testReconnect(t, strategy)
+		})
+	}
+}
+
+func testReconnect(t *testing.T, strategy Strategy) {
+	sim := simulations.NewSimNetwork()
+	clock := simulations.NewSimClock(time.Unix(0, 0))
 
 	var nodes []*network.Network
 	var plugins []*BasicPlugin
 
 	for i := 0; i < numNodes; i++ {
-		node, plugin, err := newNode(i, true, i == 0)
+		node, plugin, err := newNode(sim, clock, strategy, i, true, i == 0)
 		if err != nil {
 			t.Error(err)
 		}
@@ -126,20 +178,25 @@ func TestPlugin(t *testing.T) {
 	}
 
 	// Wait for all nodes to finish discovering other peers.
-	time.Sleep(1 * time.Second)
+	for i := 1; i < numNodes; i++ {
+		if err := sim.Connect(nodes[0], nodes[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
 
-	// chack that broadcasts are working
+	// check that broadcasts are working
 	if err := broadcastAndCheck(nodes, plugins); err != nil {
 		t.Fatal(err)
 	}
 
 	// disconnect node 2
+	if err := sim.Disconnect(nodes[0], nodes[1]); err != nil {
+		t.Fatal(err)
+	}
 	close(nodes[1].Shutdown)
 
-	glog.Infof("[Debug] waiting %s to check\n", initialDelay+defaultMinInterval*4)
-
-	// wait until about the middle of the backoff period
-	time.Sleep(initialDelay + defaultMinInterval*4)
+	// advance the virtual clock to about the middle of the backoff period
+	clock.Advance(initialDelay + defaultMinInterval*4)
 
 	// tests that broadcasting fails
 	if err := broadcastAndCheck(nodes, plugins); err == nil {
@@ -147,20 +204,122 @@ func TestPlugin(t *testing.T) {
 	}
 
 	// recreate the second node to the cluster
-	node, plugin, err := newNode(1, false, false)
+	node, plugin, err := newNode(sim, clock, strategy, 1, false, false)
 	if err != nil {
 		t.Fatal(err)
 	}
 	nodes[1] = node
 	plugins[1] = plugin
 
-	glog.Infof("[Debug] waiting %s to check\n", 5*time.Second)
+	// advance the clock past the remainder of the backoff window so the
+	// redial loop notices the peer is back
+	clock.Advance(defaultMaxInterval)
 
-	// wait for reconnection
-	time.Sleep(5 * time.Second)
+	// the redial loop reconnects on its own goroutine; wait for node 0 to
+	// actually hold a live connection before broadcasting, since a fresh
+	// dial racing the clock advance would otherwise make this flaky.
+	waitForPeer(t, nodes[0], nodes[1].Address)
 
-	// broad cast should be working again
+	// broadcast should be working again
 	if err := broadcastAndCheck(nodes, plugins); err != nil {
 		t.Fatal(err)
 	}
 }
+
+// waitForPeer polls node.Peers() until address shows up, used after a
+// simulated reconnect whose redial loop runs on its own goroutine.
+func waitForPeer(t *testing.T, node *network.Network, address string) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, client := range node.Peers() {
+			if client.Address == address {
+				return
+			}
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %s to reconnect to %s", node.Address, address)
+}
+
+// TestPluginDrainsBroadcasterAfterReconnect proves the pause/drain
+// integration between Plugin and a real broadcast.Broadcaster: a message
+// queued through BroadcastBatched while a peer is disconnected is held, not
+// dropped, and is delivered once the redial loop reconnects and resumes it.
+func TestPluginDrainsBroadcasterAfterReconnect(t *testing.T) {
+	sim := simulations.NewSimNetwork()
+	clock := simulations.NewSimClock(time.Unix(0, 0))
+
+	addr0 := network.FormatAddress(protocol, host, 200)
+	addr1 := network.FormatAddress(protocol, host, 201)
+
+	caster := broadcast.New(broadcast.Options{
+		MaxBatchSize: broadcast.DefaultMaxBatchSize,
+		MaxBatchWait: time.Millisecond,
+		MaxQueueSize: broadcast.DefaultMaxQueueSize,
+		IDFunc:       func(msg proto.Message) string { return msg.(*messages.BasicMessage).Message },
+	})
+
+	config := DefaultPluginConfig()
+	config.Clock = clock
+	config.Strategy = &Exponential{Base: defaultMinInterval, Cap: defaultMaxInterval}
+	config.Broadcaster = caster
+
+	builder0 := builders.NewNetworkBuilder()
+	builder0.SetKeys(ed25519.RandomKeyPair())
+	builder0.SetAddress(addr0)
+	builder0.AddPlugin(caster)
+	builder0.AddPlugin(&Plugin{config: config})
+
+	node0, err := sim.Adapter.NewNode(addr0, builder0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim.Add(node0)
+	go node0.Listen()
+	node0.BlockUntilListening()
+
+	plugin1 := new(BasicPlugin)
+	builder1 := builders.NewNetworkBuilder()
+	builder1.SetKeys(ed25519.RandomKeyPair())
+	builder1.SetAddress(addr1)
+	builder1.AddPlugin(plugin1)
+
+	node1, err := sim.Adapter.NewNode(addr1, builder1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim.Add(node1)
+	go node1.Listen()
+	node1.BlockUntilListening()
+
+	if err := sim.Connect(node0, node1); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sim.Disconnect(node0, node1); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := &messages.BasicMessage{Message: "queued during outage"}
+	caster.BroadcastBatched(msg)
+
+	select {
+	case <-plugin1.Mailbox:
+		t.Fatal("message was delivered while the peer was disconnected")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	clock.Advance(initialDelay)
+	waitForPeer(t, node0, addr1)
+
+	select {
+	case received := <-plugin1.Mailbox:
+		if received.Message != msg.Message {
+			t.Fatalf("expected %q, got %q", msg.Message, received.Message)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the queued broadcast to be delivered after reconnect")
+	}
+}
@@ -0,0 +1,88 @@
+package backoff
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Strategy computes the delay before the next reconnect attempt. Next is
+// called once per failed attempt: attempt is the zero-based index of the
+// failed attempt within this reconnect cycle, and last is the delay the
+// caller computed for it, before any scaling of its own. Reset clears any state a Strategy carries
+// between reconnect cycles, such as DecorrelatedJitter's memory of the
+// previous delay, and is called once at the start of every redial loop.
+type Strategy interface {
+	Next(attempt int, last time.Duration) time.Duration
+	Reset()
+}
+
+// Exponential doubles the delay every attempt, starting at Base and never
+// exceeding Cap. It is plain exponential backoff and, like any strategy
+// without randomness, synchronizes badly when many peers back off against
+// the same downed node at once.
+type Exponential struct {
+	Base time.Duration
+	Cap  time.Duration
+}
+
+// Next returns min(Cap, Base*2^attempt).
+func (e *Exponential) Next(attempt int, last time.Duration) time.Duration {
+	return capped(e.Base<<uint(attempt), e.Cap)
+}
+
+// Reset is a no-op; Exponential carries no state between cycles.
+func (e *Exponential) Reset() {}
+
+// FullJitter picks a uniformly random delay between 0 and the exponential
+// curve's value for this attempt, per AWS's "Exponential Backoff And
+// Jitter" writeup. It avoids synchronized retries without needing to
+// remember the previous delay.
+type FullJitter struct {
+	Base time.Duration
+	Cap  time.Duration
+}
+
+// Next returns rand(0, min(Cap, Base*2^attempt)).
+func (f *FullJitter) Next(attempt int, last time.Duration) time.Duration {
+	ceiling := capped(f.Base<<uint(attempt), f.Cap)
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// Reset is a no-op; FullJitter carries no state between cycles.
+func (f *FullJitter) Reset() {}
+
+// DecorrelatedJitter picks a uniformly random delay between Base and three
+// times the previous delay, capped at Cap. It spreads retries out further
+// than FullJitter while still growing roughly exponentially, and is reset
+// to Base at the start of every reconnect cycle.
+type DecorrelatedJitter struct {
+	Base time.Duration
+	Cap  time.Duration
+}
+
+// Next returns min(Cap, rand(Base, last*3)), treating last as Base on the
+// first attempt since Reset (attempt 0) or whenever last is zero.
+func (d *DecorrelatedJitter) Next(attempt int, last time.Duration) time.Duration {
+	if attempt == 0 || last == 0 {
+		last = d.Base
+	}
+
+	upper := capped(last*3, d.Cap)
+	if upper <= d.Base {
+		return d.Base
+	}
+	return d.Base + time.Duration(rand.Int63n(int64(upper-d.Base)+1))
+}
+
+// Reset is a no-op; DecorrelatedJitter derives its next window from last
+// rather than from stored state, seeding from Base on attempt 0.
+func (d *DecorrelatedJitter) Reset() {}
+
+// capped returns d clamped to cap, treating an overflowed or non-positive d
+// (from a large left shift) as having already hit the cap.
+func capped(d, cap time.Duration) time.Duration {
+	if d <= 0 || d > cap {
+		return cap
+	}
+	return d
+}
@@ -0,0 +1,174 @@
+// Package broadcast provides a batched, coalesced alternative to
+// network.Network's synchronous Broadcast: one goroutine and mailbox per
+// recipient, so a slow or reconnecting peer no longer serializes every send
+// on the caller's goroutine.
+package broadcast
+
+import (
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/perlin-network/noise/network"
+)
+
+const (
+	// DefaultMaxBatchSize is the number of queued messages that triggers an
+	// immediate flush to a peer.
+	DefaultMaxBatchSize = 16
+
+	// DefaultMaxBatchWait is how long a peer's mailbox waits, once the
+	// first unsent message arrives, before flushing regardless of size.
+	DefaultMaxBatchWait = 50 * time.Millisecond
+
+	// DefaultMaxQueueSize bounds how many unsent messages may be queued for
+	// a single peer; past it, the oldest unsent message is dropped to make
+	// room for each new one.
+	DefaultMaxQueueSize = 128
+)
+
+// Options configures a Broadcaster.
+type Options struct {
+	MaxBatchSize int
+	MaxBatchWait time.Duration
+	MaxQueueSize int
+
+	// IDFunc extracts a dedupe key from a message; messages with the same
+	// ID already queued for a peer are coalesced into a single send.
+	IDFunc func(msg proto.Message) string
+}
+
+// DefaultOptions returns the Options used when a Broadcaster is created
+// without explicit overrides. IDFunc must still be supplied by the caller,
+// since message identity is application-specific.
+func DefaultOptions(idFunc func(msg proto.Message) string) Options {
+	return Options{
+		MaxBatchSize: DefaultMaxBatchSize,
+		MaxBatchWait: DefaultMaxBatchWait,
+		MaxQueueSize: DefaultMaxQueueSize,
+		IDFunc:       idFunc,
+	}
+}
+
+// Broadcaster fans a message out to every peer of a network.Network through
+// a per-peer channeledSender instead of sending on the caller's goroutine.
+// It is itself a network.PluginInterface so its senders' teardown is tied to
+// the network's own lifetime: Cleanup closes every sender once the network
+// shuts down, rather than leaking their goroutines forever.
+type Broadcaster struct {
+	*network.Plugin
+
+	opts Options
+
+	mu      sync.Mutex
+	senders map[string]*channeledSender
+}
+
+// New creates a Broadcaster using opts. Register it with a
+// builders.NetworkBuilder via AddPlugin so its PeerConnect registers a
+// sender for every peer as it connects, and its Cleanup tears down every
+// sender goroutine once that network shuts down.
+func New(opts Options) *Broadcaster {
+	return &Broadcaster{
+		opts:    opts,
+		senders: make(map[string]*channeledSender),
+	}
+}
+
+// Cleanup closes every peer's channeledSender, so none of their goroutines
+// outlive the network they were fanning out over.
+func (b *Broadcaster) Cleanup(net *network.Network) {
+	b.mu.Lock()
+	senders := b.senders
+	b.senders = make(map[string]*channeledSender)
+	b.mu.Unlock()
+
+	for _, sender := range senders {
+		sender.Close()
+	}
+}
+
+// PeerConnect registers a sender for client, so a peer that is currently
+// paused mid-reconnect still has somewhere for BroadcastBatched to queue
+// messages, and a freshly (re)connected peer's sender is redirected to the
+// new connection.
+func (b *Broadcaster) PeerConnect(client *network.PeerClient) {
+	b.sender(client)
+}
+
+// BroadcastBatched enqueues msg on every known peer's mailbox, including
+// peers currently paused mid-reconnect; their messages simply wait for
+// Resume. Unlike network.Network.Broadcast, this returns as soon as the
+// message is queued; the per-peer sender goroutines flush it according to
+// Options.
+func (b *Broadcaster) BroadcastBatched(msg proto.Message) {
+	b.mu.Lock()
+	senders := make([]*channeledSender, 0, len(b.senders))
+	for _, sender := range b.senders {
+		senders = append(senders, sender)
+	}
+	b.mu.Unlock()
+
+	for _, sender := range senders {
+		sender.Enqueue(msg)
+	}
+}
+
+// Pause stops flushing to the peer at address, used by the backoff plugin
+// while a reconnect to that peer is in progress.
+func (b *Broadcaster) Pause(address string) {
+	b.mu.Lock()
+	sender, ok := b.senders[address]
+	b.mu.Unlock()
+	if ok {
+		sender.Pause()
+	}
+}
+
+// Resume re-enables flushing to the peer at address and immediately drains
+// whatever queued up while it was paused.
+func (b *Broadcaster) Resume(address string) {
+	b.mu.Lock()
+	sender, ok := b.senders[address]
+	b.mu.Unlock()
+	if ok {
+		sender.Resume()
+	}
+}
+
+// sender returns the channeledSender for client, creating one if this is
+// the first time client's address has been seen, or redirecting an
+// existing (possibly paused) sender to client if it was created against an
+// earlier, now-dead connection to the same address.
+func (b *Broadcaster) sender(client *network.PeerClient) *channeledSender {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sender, ok := b.senders[client.Address]
+	if !ok {
+		sender = newChanneledSender(client.Address, client, b.opts)
+		b.senders[client.Address] = sender
+	} else {
+		sender.setSender(client)
+	}
+	return sender
+}
+
+// notifyBroadcastRequested and notifyBroadcastDropped are metrics hooks
+// operators can override to observe queue pressure; they default to no-ops.
+var (
+	notifyBroadcastRequested = func(address string, count int) {}
+	notifyBroadcastDropped   = func(address string, count int) {}
+)
+
+// SetMetricsHooks installs callbacks invoked whenever a message is queued
+// (requested) or dropped due to a full mailbox, keyed by peer address.
+func SetMetricsHooks(onRequested, onDropped func(address string, count int)) {
+	if onRequested != nil {
+		notifyBroadcastRequested = onRequested
+	}
+	if onDropped != nil {
+		notifyBroadcastDropped = onDropped
+	}
+}
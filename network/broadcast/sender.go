@@ -0,0 +1,178 @@
+package broadcast
+
+import (
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// peerSender is the subset of *network.PeerClient a channeledSender needs,
+// broken out as an interface so tests can drive the sender without a real
+// network connection.
+type peerSender interface {
+	Tell(msg proto.Message)
+}
+
+// inboxBuffer sizes the channel feeding the sender's run loop. It is just a
+// communication pipe to that single goroutine, so it is sized generously and
+// independently of Options.MaxQueueSize, which bounds the unsent mailbox
+// itself via drop-oldest in run().
+const inboxBuffer = 256
+
+// channeledSender owns the mailbox for a single peer: messages queued
+// through Enqueue are coalesced into batches and flushed either once
+// MaxBatchSize messages are pending or MaxBatchWait has elapsed since the
+// first unsent message, whichever comes first. A channeledSender can be
+// paused while its peer is mid-reconnect; messages keep queuing, oldest
+// first, up to MaxQueueSize and are flushed in order once it is resumed.
+// Past MaxQueueSize the oldest unsent message is dropped to make room for
+// the new one, and notifyBroadcastDropped is called so operators can
+// observe the queue pressure.
+type channeledSender struct {
+	address string
+	sender  peerSender
+	opts    Options
+
+	incoming chan proto.Message
+	outgoing chan []proto.Message
+
+	mu        sync.Mutex
+	paused    bool
+	unsent    []proto.Message
+	unsentIDs map[string]bool
+
+	stop chan struct{}
+}
+
+func newChanneledSender(address string, sender peerSender, opts Options) *channeledSender {
+	s := &channeledSender{
+		address:   address,
+		sender:    sender,
+		opts:      opts,
+		incoming:  make(chan proto.Message, inboxBuffer),
+		outgoing:  make(chan []proto.Message, 1),
+		unsentIDs: make(map[string]bool),
+		stop:      make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// Enqueue adds msg to the peer's mailbox, deduping against any unsent
+// message that shares the same ID as reported by Options.IDFunc.
+func (s *channeledSender) Enqueue(msg proto.Message) {
+	select {
+	case s.incoming <- msg:
+		notifyBroadcastRequested(s.address, 1)
+	default:
+		notifyBroadcastDropped(s.address, 1)
+	}
+}
+
+// Pause stops the sender from flushing batches to its peer; messages keep
+// accumulating in the mailbox until Resume is called.
+func (s *channeledSender) Pause() {
+	s.mu.Lock()
+	s.paused = true
+	s.mu.Unlock()
+}
+
+// Resume re-enables flushing and immediately drains any batch that queued
+// up while paused.
+func (s *channeledSender) Resume() {
+	s.mu.Lock()
+	s.paused = false
+	s.mu.Unlock()
+	s.maybeFlush(true)
+}
+
+// Close stops the sender's goroutine; any unsent messages are dropped.
+func (s *channeledSender) Close() {
+	close(s.stop)
+}
+
+// setSender redirects future flushes to sender, used when a peer
+// reconnects under a fresh PeerClient so a sender paused across the outage
+// flushes to the new connection instead of the dead one it was created
+// with.
+func (s *channeledSender) setSender(sender peerSender) {
+	s.mu.Lock()
+	s.sender = sender
+	s.mu.Unlock()
+}
+
+func (s *channeledSender) run() {
+	var timer *time.Timer
+	var timerC <-chan time.Time
+
+	for {
+		select {
+		case msg := <-s.incoming:
+			s.mu.Lock()
+			id := s.opts.IDFunc(msg)
+			if !s.unsentIDs[id] {
+				if len(s.unsent) >= s.opts.MaxQueueSize {
+					dropped := s.unsent[0]
+					s.unsent = s.unsent[1:]
+					delete(s.unsentIDs, s.opts.IDFunc(dropped))
+					notifyBroadcastDropped(s.address, 1)
+				}
+
+				s.unsentIDs[id] = true
+				s.unsent = append(s.unsent, msg)
+				if len(s.unsent) == 1 {
+					timer = time.NewTimer(s.opts.MaxBatchWait)
+					timerC = timer.C
+				}
+			}
+			full := len(s.unsent) >= s.opts.MaxBatchSize
+			s.mu.Unlock()
+
+			if full {
+				if timer != nil {
+					timer.Stop()
+					timerC = nil
+				}
+				s.maybeFlush(false)
+			}
+		case <-timerC:
+			timerC = nil
+			s.maybeFlush(false)
+		case <-s.stop:
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		}
+	}
+}
+
+// maybeFlush sends the pending batch to the peer unless the sender is
+// paused, in which case the batch is left queued for the next Resume.
+func (s *channeledSender) maybeFlush(force bool) {
+	s.mu.Lock()
+	if s.paused && !force {
+		s.mu.Unlock()
+		return
+	}
+	if len(s.unsent) == 0 {
+		s.mu.Unlock()
+		return
+	}
+
+	batch := s.unsent
+	s.unsent = nil
+	s.unsentIDs = make(map[string]bool)
+	sender := s.sender
+	s.mu.Unlock()
+
+	for _, msg := range batch {
+		sender.Tell(msg)
+	}
+
+	select {
+	case s.outgoing <- batch:
+	default:
+	}
+}
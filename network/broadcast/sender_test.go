@@ -0,0 +1,105 @@
+package broadcast
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// fakeMessage is a minimal proto.Message used to exercise channeledSender
+// without a real noise message type.
+type fakeMessage struct{ id string }
+
+func (m *fakeMessage) Reset()         {}
+func (m *fakeMessage) String() string { return m.id }
+func (m *fakeMessage) ProtoMessage()  {}
+
+// fakeSender records, in order, every message handed to Tell.
+type fakeSender struct {
+	mu  sync.Mutex
+	got []string
+}
+
+func (f *fakeSender) Tell(msg proto.Message) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.got = append(f.got, msg.(*fakeMessage).id)
+}
+
+func (f *fakeSender) received() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make([]string, len(f.got))
+	copy(out, f.got)
+	return out
+}
+
+// TestChanneledSenderDropsOldestPastCapacity pauses a sender, enqueues more
+// messages than MaxQueueSize allows, and checks that the oldest unsent
+// messages are dropped (with notifyBroadcastDropped firing) rather than the
+// mailbox growing without bound, then that Resume drains the survivors in
+// order.
+func TestChanneledSenderDropsOldestPastCapacity(t *testing.T) {
+	var dropped int
+	SetMetricsHooks(nil, func(address string, count int) {
+		dropped += count
+	})
+	defer SetMetricsHooks(func(string, int) {}, func(string, int) {})
+
+	opts := Options{
+		MaxBatchSize: 10,
+		MaxBatchWait: time.Hour,
+		MaxQueueSize: 3,
+		IDFunc:       func(msg proto.Message) string { return msg.(*fakeMessage).id },
+	}
+
+	sender := &fakeSender{}
+	s := newChanneledSender("peer", sender, opts)
+	defer s.Close()
+
+	s.Pause()
+
+	for _, id := range []string{"1", "2", "3", "4", "5"} {
+		s.Enqueue(&fakeMessage{id: id})
+	}
+
+	waitForUnsent(t, s, []string{"3", "4", "5"})
+
+	if dropped != 2 {
+		t.Fatalf("expected 2 dropped messages, got %d", dropped)
+	}
+
+	s.Resume()
+
+	got := sender.received()
+	want := []string{"3", "4", "5"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected drained order %v, got %v", want, got)
+	}
+}
+
+// waitForUnsent polls until s's unsent mailbox holds exactly the messages
+// with the given ids, in order. Waiting on the contents rather than the
+// length matters: the mailbox already holds 3 messages before any drop.
+func waitForUnsent(t *testing.T, s *channeledSender, want []string) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		s.mu.Lock()
+		current := make([]string, len(s.unsent))
+		for i, msg := range s.unsent {
+			current[i] = msg.(*fakeMessage).id
+		}
+		s.mu.Unlock()
+
+		if reflect.DeepEqual(current, want) {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for unsent messages %v", want)
+}
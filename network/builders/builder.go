@@ -0,0 +1,51 @@
+// Package builders assembles network.Network instances from a key pair, an
+// address, an optional Transport, and an ordered list of plugins.
+package builders
+
+import (
+	"github.com/perlin-network/noise/crypto"
+	"github.com/perlin-network/noise/network"
+)
+
+// NetworkBuilder collects the configuration a Network needs before it can be
+// built; each setter is independent, so callers only set what they need to
+// override.
+type NetworkBuilder struct {
+	keys      *crypto.KeyPair
+	address   string
+	transport network.Transport
+	plugins   []network.PluginInterface
+}
+
+// NewNetworkBuilder returns an empty NetworkBuilder.
+func NewNetworkBuilder() *NetworkBuilder {
+	return &NetworkBuilder{}
+}
+
+// SetKeys sets the key pair the built Network identifies itself with.
+func (b *NetworkBuilder) SetKeys(keys *crypto.KeyPair) {
+	b.keys = keys
+}
+
+// SetAddress sets the address the built Network listens on and is dialed
+// through.
+func (b *NetworkBuilder) SetAddress(address string) {
+	b.address = address
+}
+
+// SetTransport overrides the Transport the built Network dials and listens
+// through; it defaults to real TCP sockets when never called.
+func (b *NetworkBuilder) SetTransport(transport network.Transport) {
+	b.transport = transport
+}
+
+// AddPlugin registers plugin with the built Network, in call order; plugins
+// see every lifecycle event and message in the order they were added.
+func (b *NetworkBuilder) AddPlugin(plugin network.PluginInterface) {
+	b.plugins = append(b.plugins, plugin)
+}
+
+// Build constructs the Network described by the builder so far.
+func (b *NetworkBuilder) Build() (*network.Network, error) {
+	return network.New(b.address, b.keys, b.transport, b.plugins)
+}
@@ -0,0 +1,29 @@
+package network
+
+import "time"
+
+// Clock abstracts away wall-clock time so that time-driven components, such
+// as the backoff plugin's reconnect loop, can be driven deterministically by
+// a virtual clock under test instead of sleeping for real.
+type Clock interface {
+	// Now returns the clock's current time.
+	Now() time.Time
+
+	// After behaves like time.After: it returns a channel that receives the
+	// clock's current time once d has elapsed.
+	After(d time.Duration) <-chan time.Time
+
+	// Sleep blocks the calling goroutine until d has elapsed on the clock.
+	Sleep(d time.Duration)
+}
+
+// realClock is the Clock implementation backed by the standard library; it
+// is used wherever no virtual Clock has been supplied.
+type realClock struct{}
+
+// RealClock is the default Clock, backed by the actual wall clock.
+var RealClock Clock = realClock{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) Sleep(d time.Duration)                  { time.Sleep(d) }
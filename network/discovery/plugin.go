@@ -0,0 +1,12 @@
+// Package discovery provides the minimal peer discovery plugin bootstrapped
+// nodes register so they are reachable by address alone.
+package discovery
+
+import "github.com/perlin-network/noise/network"
+
+// Plugin marks a node as participating in peer discovery. In its current,
+// single-hop form it adds no behavior beyond network.Plugin's defaults;
+// gossiping peer lists for multi-hop discovery is not yet implemented.
+type Plugin struct {
+	*network.Plugin
+}
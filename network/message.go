@@ -0,0 +1,21 @@
+package network
+
+import "github.com/golang/protobuf/proto"
+
+// MessageContext carries a single inbound message through every plugin's
+// Receive hook.
+type MessageContext struct {
+	sender  *PeerClient
+	self    *PeerClient
+	message proto.Message
+}
+
+// Sender is the peer the message arrived from.
+func (ctx *MessageContext) Sender() *PeerClient { return ctx.sender }
+
+// Self is the local node's own view of the connection the message arrived
+// on.
+func (ctx *MessageContext) Self() *PeerClient { return ctx.self }
+
+// Message is the decoded message body.
+func (ctx *MessageContext) Message() proto.Message { return ctx.message }
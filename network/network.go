@@ -0,0 +1,292 @@
+package network
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/golang/glog"
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+
+	"github.com/perlin-network/noise/crypto"
+)
+
+// FormatAddress builds the dialable address a Network is identified by, of
+// the form "protocol://host:port".
+func FormatAddress(protocol, host string, port uint16) string {
+	return fmt.Sprintf("%s://%s:%d", protocol, host, port)
+}
+
+// Network hosts a single node's view of the cluster: it listens for inbound
+// connections, dials peers on demand, and dispatches every inbound message
+// and lifecycle event to its registered plugins in registration order.
+type Network struct {
+	Address string
+	Keys    *crypto.KeyPair
+
+	transport Transport
+	plugins   []PluginInterface
+
+	listener  net.Listener
+	listening chan struct{}
+
+	mu    sync.Mutex
+	peers map[string]*PeerClient
+
+	// Shutdown is closed to tear the network down: every open peer
+	// connection and the listener are closed, and every plugin's Cleanup
+	// is called.
+	Shutdown chan struct{}
+}
+
+// New builds a Network bound to address, identified by keys, dialing and
+// listening through transport. A nil transport defaults to real TCP
+// sockets. Every plugin's Startup is called synchronously before New
+// returns.
+func New(address string, keys *crypto.KeyPair, transport Transport, plugins []PluginInterface) (*Network, error) {
+	if transport == nil {
+		transport = tcpTransport{}
+	}
+
+	n := &Network{
+		Address:   address,
+		Keys:      keys,
+		transport: transport,
+		plugins:   plugins,
+		listening: make(chan struct{}),
+		peers:     make(map[string]*PeerClient),
+		Shutdown:  make(chan struct{}),
+	}
+
+	for _, plugin := range n.plugins {
+		plugin.Startup(n)
+	}
+
+	go n.watchShutdown()
+
+	return n, nil
+}
+
+// Listen starts accepting inbound connections on n.Address, blocking until
+// the listener itself is closed or fails. BlockUntilListening unblocks once
+// the listener is ready.
+func (n *Network) Listen() error {
+	listener, err := n.transport.Listen(addressToHostPort(n.Address))
+	if err != nil {
+		return errors.Wrapf(err, "failed to listen on %s", n.Address)
+	}
+	n.listener = listener
+	close(n.listening)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			select {
+			case <-n.Shutdown:
+				return nil
+			default:
+				return err
+			}
+		}
+		go n.handleConn(conn)
+	}
+}
+
+// BlockUntilListening blocks until Listen has successfully bound its
+// listener.
+func (n *Network) BlockUntilListening() {
+	<-n.listening
+}
+
+// Bootstrap dials every address, logging (but not returning) any dial that
+// fails, so that one unreachable seed does not stop the others from
+// bootstrapping.
+func (n *Network) Bootstrap(addresses ...string) {
+	for _, address := range addresses {
+		if _, err := n.Client(address); err != nil {
+			glog.Errorf("network: failed to bootstrap to %s: %v", address, err)
+		}
+	}
+}
+
+// Client returns the PeerClient for address, dialing it if this is the
+// first time it has been addressed.
+func (n *Network) Client(address string) (*PeerClient, error) {
+	n.mu.Lock()
+	if client, ok := n.peers[address]; ok {
+		n.mu.Unlock()
+		return client, nil
+	}
+	n.mu.Unlock()
+
+	conn, err := n.transport.Dial(addressToHostPort(address))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to dial %s", address)
+	}
+
+	client := newPeerClient(n, address, conn)
+	if err := client.enc.Encode(frame{Message: &helloMessage{Address: n.Address}}); err != nil {
+		conn.Close()
+		return nil, errors.Wrapf(err, "failed to hello %s", address)
+	}
+
+	n.registerPeer(client)
+	n.notifyPeerConnect(client)
+	go n.readLoop(client)
+
+	return client, nil
+}
+
+// Broadcast sends message to every currently connected peer. message must
+// implement proto.Message; anything else is logged and dropped.
+func (n *Network) Broadcast(message interface{}) {
+	msg, ok := message.(proto.Message)
+	if !ok {
+		glog.Errorf("network: Broadcast called with a non proto.Message: %T", message)
+		return
+	}
+	for _, client := range n.Peers() {
+		client.Tell(msg)
+	}
+}
+
+// Peers returns every peer this network is currently connected to.
+func (n *Network) Peers() []*PeerClient {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	peers := make([]*PeerClient, 0, len(n.peers))
+	for _, client := range n.peers {
+		peers = append(peers, client)
+	}
+	return peers
+}
+
+// handleConn services a freshly-accepted inbound connection: it reads the
+// dialer's hello frame to learn the address to key it by, then hands off to
+// readLoop like any other peer.
+func (n *Network) handleConn(conn net.Conn) {
+	client := newPeerClient(n, "", conn)
+
+	var f frame
+	if err := client.dec.Decode(&f); err != nil {
+		conn.Close()
+		return
+	}
+	hello, ok := f.Message.(*helloMessage)
+	if !ok {
+		conn.Close()
+		return
+	}
+	client.Address = hello.Address
+
+	n.registerPeer(client)
+	n.notifyPeerConnect(client)
+	n.readLoop(client)
+}
+
+// readLoop decodes frames off client until its connection fails, dispatching
+// every message to Receive, and removes the peer once the loop exits.
+func (n *Network) readLoop(client *PeerClient) {
+	defer n.removePeer(client)
+
+	for {
+		var f frame
+		if err := client.dec.Decode(&f); err != nil {
+			return
+		}
+		if _, ok := f.Message.(*helloMessage); ok {
+			continue
+		}
+
+		ctx := &MessageContext{
+			sender:  client,
+			self:    &PeerClient{Address: n.Address, net: n},
+			message: f.Message,
+		}
+		n.dispatchReceive(ctx)
+	}
+}
+
+// dispatchReceive runs ctx through every plugin's Receive in order. A
+// non-nil error is logged and reported to every plugin implementing
+// ReceiveErrorObserver, but does not stop later plugins from seeing the
+// message.
+func (n *Network) dispatchReceive(ctx *MessageContext) {
+	for _, plugin := range n.plugins {
+		if err := plugin.Receive(ctx); err != nil {
+			glog.Errorf("network: plugin Receive error for message from %s: %v", ctx.Sender().Address, err)
+			for _, observer := range n.plugins {
+				if o, ok := observer.(ReceiveErrorObserver); ok {
+					o.ObserveReceiveError(ctx, err)
+				}
+			}
+		}
+	}
+}
+
+func (n *Network) registerPeer(client *PeerClient) {
+	n.mu.Lock()
+	n.peers[client.Address] = client
+	n.mu.Unlock()
+}
+
+func (n *Network) removePeer(client *PeerClient) {
+	n.mu.Lock()
+	existing, ok := n.peers[client.Address]
+	if ok && existing == client {
+		delete(n.peers, client.Address)
+	}
+	n.mu.Unlock()
+
+	if ok && existing == client {
+		n.notifyPeerDisconnect(client)
+	}
+}
+
+func (n *Network) notifyPeerConnect(client *PeerClient) {
+	for _, plugin := range n.plugins {
+		plugin.PeerConnect(client)
+	}
+}
+
+func (n *Network) notifyPeerDisconnect(client *PeerClient) {
+	for _, plugin := range n.plugins {
+		plugin.PeerDisconnect(client)
+	}
+}
+
+// watchShutdown waits for Shutdown to close, then tears down every open peer
+// connection and the listener, and finally calls every plugin's Cleanup.
+func (n *Network) watchShutdown() {
+	<-n.Shutdown
+
+	n.mu.Lock()
+	peers := make([]*PeerClient, 0, len(n.peers))
+	for _, client := range n.peers {
+		peers = append(peers, client)
+	}
+	n.peers = make(map[string]*PeerClient)
+	n.mu.Unlock()
+
+	for _, client := range peers {
+		client.Close()
+	}
+	if n.listener != nil {
+		n.listener.Close()
+	}
+	for _, plugin := range n.plugins {
+		plugin.Cleanup(n)
+	}
+}
+
+// addressToHostPort strips a "protocol://" prefix, if any, leaving the
+// "host:port" pair a Transport dials or listens on.
+func addressToHostPort(address string) string {
+	if idx := strings.Index(address, "://"); idx != -1 {
+		return address[idx+len("://"):]
+	}
+	return address
+}
@@ -0,0 +1,54 @@
+package network
+
+import (
+	"bufio"
+	"encoding/gob"
+	"net"
+	"sync"
+
+	"github.com/golang/glog"
+	"github.com/golang/protobuf/proto"
+)
+
+// PeerClient represents a single connection to a remote peer, shared by
+// every plugin that wants to Tell it something.
+type PeerClient struct {
+	Address string
+
+	net  *Network
+	conn net.Conn
+
+	encMu sync.Mutex
+	enc   *gob.Encoder
+	dec   *gob.Decoder
+}
+
+// newPeerClient wraps conn, ready to both send and receive frames.
+func newPeerClient(net *Network, address string, conn net.Conn) *PeerClient {
+	return &PeerClient{
+		Address: address,
+		net:     net,
+		conn:    conn,
+		enc:     gob.NewEncoder(conn),
+		dec:     gob.NewDecoder(bufio.NewReader(conn)),
+	}
+}
+
+// Tell sends msg to this peer. Errors writing to the connection are logged
+// rather than returned, matching Network.Broadcast's fire-and-forget
+// semantics; a peer that can no longer be written to will surface as a
+// PeerDisconnect once its read loop notices instead.
+func (c *PeerClient) Tell(msg proto.Message) {
+	c.encMu.Lock()
+	defer c.encMu.Unlock()
+
+	if err := c.enc.Encode(frame{Message: msg}); err != nil {
+		glog.Errorf("network: failed to tell %s: %v", c.Address, err)
+	}
+}
+
+// Close tears down the underlying connection, triggering a PeerDisconnect
+// for this client once its read loop observes the close.
+func (c *PeerClient) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,45 @@
+package network
+
+// PluginInterface is implemented by everything that wants to observe or
+// react to network lifecycle and message events. Plugin is the no-op base
+// every concrete plugin embeds so it only has to override the hooks it
+// cares about.
+type PluginInterface interface {
+	// Startup is called once, synchronously, before the network starts
+	// listening.
+	Startup(net *Network)
+
+	// Receive is called for every inbound message, in registration order.
+	// A non-nil error is logged and does not stop later plugins from
+	// seeing the message, but is reported to any plugin implementing
+	// ReceiveErrorObserver.
+	Receive(ctx *MessageContext) error
+
+	// PeerConnect is called whenever a new peer connection is established.
+	PeerConnect(client *PeerClient)
+
+	// PeerDisconnect is called whenever a peer connection is torn down.
+	PeerDisconnect(client *PeerClient)
+
+	// Cleanup is called once the network's Shutdown channel is closed, so
+	// plugins can release any resources (goroutines, open connections)
+	// they hold.
+	Cleanup(net *Network)
+}
+
+// Plugin is a no-op PluginInterface meant to be embedded by name in concrete
+// plugin types, e.g. `type MyPlugin struct { *network.Plugin }`.
+type Plugin struct{}
+
+func (*Plugin) Startup(net *Network)              {}
+func (*Plugin) Receive(ctx *MessageContext) error { return nil }
+func (*Plugin) PeerConnect(client *PeerClient)    {}
+func (*Plugin) PeerDisconnect(client *PeerClient) {}
+func (*Plugin) Cleanup(net *Network)              {}
+
+// ReceiveErrorObserver is implemented by plugins that want to know when
+// another plugin's Receive returned a non-nil error while processing a
+// message, such as the backoff plugin scaling its awareness score off of it.
+type ReceiveErrorObserver interface {
+	ObserveReceiveError(ctx *MessageContext, err error)
+}
@@ -0,0 +1,141 @@
+// Package simulations provides an in-process adapter for wiring up
+// network.Network instances without binding real TCP ports, inspired by
+// go-ethereum's p2p/simulations/adapters and pipes packages. It lets tests
+// for reconnection, partition healing, and jitter run fast and
+// deterministically instead of pinning ports and sleeping on wall-clock
+// time.
+package simulations
+
+import (
+	"net"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/perlin-network/noise/network"
+	"github.com/perlin-network/noise/network/builders"
+)
+
+// InprocAdapter builds network.Network instances whose connections to one
+// another are backed by net.Pipe rather than a real listener, keyed by the
+// address each node was built with.
+type InprocAdapter struct {
+	mu        sync.Mutex
+	listeners map[string]*inprocListener
+}
+
+// NewInprocAdapter returns an empty InprocAdapter ready to wire up nodes
+// registered with NewNode.
+func NewInprocAdapter() *InprocAdapter {
+	return &InprocAdapter{listeners: make(map[string]*inprocListener)}
+}
+
+// NewNode builds a network.Network from builder, bound to address, whose
+// transport is redirected through this adapter: dials to another address
+// registered with this adapter hand back the client half of an in-memory
+// net.Pipe whose server half is delivered to that address's listener.
+func (a *InprocAdapter) NewNode(address string, builder *builders.NetworkBuilder) (*network.Network, error) {
+	builder.SetTransport(&inprocTransport{self: address, adapter: a})
+	return builder.Build()
+}
+
+// inprocTransport is the per-node view of an InprocAdapter: it remembers
+// which address is dialing so the adapter can tell apart the two ends of a
+// connection between two distinct nodes.
+type inprocTransport struct {
+	self    string
+	adapter *InprocAdapter
+}
+
+// Dial implements network.Transport by handing back the client half of a
+// net.Pipe whose server half is delivered to address's listener.
+func (t *inprocTransport) Dial(address string) (net.Conn, error) {
+	return t.adapter.dial(t.self, address)
+}
+
+// Listen implements network.Transport by registering (or returning) the
+// listener address will receive inbound connections on.
+func (t *inprocTransport) Listen(address string) (net.Listener, error) {
+	return t.adapter.listen(address)
+}
+
+// listen registers, or returns the already-registered, listener for address.
+// A closed listener left behind by a node that has since shut down is
+// replaced rather than reused, so a new node built at the same address (as
+// simulations.SimNetwork tests do when recreating a disconnected peer) gets
+// a fresh, open listener instead of one that will only ever report closed.
+func (a *InprocAdapter) listen(address string) (*inprocListener, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if l, ok := a.listeners[address]; ok {
+		select {
+		case <-l.closed:
+		default:
+			return l, nil
+		}
+	}
+
+	l := newInprocListener(address)
+	a.listeners[address] = l
+	return l, nil
+}
+
+// dial creates a fresh net.Pipe for the connection from -> to, delivers the
+// server half to to's listener, and returns the client half to the caller.
+func (a *InprocAdapter) dial(from, to string) (net.Conn, error) {
+	a.mu.Lock()
+	target, ok := a.listeners[to]
+	a.mu.Unlock()
+	if !ok {
+		return nil, errors.Errorf("inproc: no listener registered for %s (dialed from %s)", to, from)
+	}
+
+	client, server := net.Pipe()
+
+	select {
+	case target.conns <- server:
+		return client, nil
+	case <-target.closed:
+		return nil, errors.Errorf("inproc: listener for %s is closed", to)
+	}
+}
+
+// inprocListener hands out the server half of pipes dialed through the
+// owning InprocAdapter.
+type inprocListener struct {
+	address string
+	conns   chan net.Conn
+	closed  chan struct{}
+}
+
+func newInprocListener(address string) *inprocListener {
+	return &inprocListener{
+		address: address,
+		conns:   make(chan net.Conn),
+		closed:  make(chan struct{}),
+	}
+}
+
+func (l *inprocListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *inprocListener) Close() error {
+	close(l.closed)
+	return nil
+}
+
+func (l *inprocListener) Addr() net.Addr {
+	return inprocAddr(l.address)
+}
+
+type inprocAddr string
+
+func (a inprocAddr) Network() string { return "inproc" }
+func (a inprocAddr) String() string  { return string(a) }
@@ -0,0 +1,75 @@
+package simulations
+
+import (
+	"sync"
+	"time"
+)
+
+// SimClock is a virtual network.Clock whose time only moves when Advance is
+// called. It lets tests drive the backoff plugin's reconnect loop through
+// exact, deterministic windows instead of sleeping on the wall clock.
+type SimClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []clockWaiter
+}
+
+type clockWaiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+// NewSimClock returns a SimClock starting at the given time.
+func NewSimClock(start time.Time) *SimClock {
+	return &SimClock{now: start}
+}
+
+// Now returns the clock's current virtual time.
+func (c *SimClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After returns a channel that fires with the clock's virtual time once the
+// clock has been Advanced by at least d.
+func (c *SimClock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	deadline := c.now.Add(d)
+	if !deadline.After(c.now) {
+		ch <- c.now
+		return ch
+	}
+
+	c.waiters = append(c.waiters, clockWaiter{deadline: deadline, ch: ch})
+	return ch
+}
+
+// Sleep blocks the calling goroutine until the clock has been Advanced by at
+// least d.
+func (c *SimClock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+// Advance moves the clock forward by d, firing every waiter whose deadline
+// has now passed.
+func (c *SimClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	now := c.now
+
+	var pending []clockWaiter
+	for _, w := range c.waiters {
+		if !w.deadline.After(now) {
+			w.ch <- now
+		} else {
+			pending = append(pending, w)
+		}
+	}
+	c.waiters = pending
+	c.mu.Unlock()
+}
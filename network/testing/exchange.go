@@ -0,0 +1,91 @@
+// Package testing provides the canonical way plugin authors write
+// integration tests against a noise network: describe the messages an
+// Exchange sends and the messages it expects back, and let the harness
+// worry about which node's mailbox produces them in what order. It mirrors
+// the "trigger/expect" model of go-ethereum's ProtocolSession.TestExchanges.
+package testing
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/perlin-network/noise/network"
+)
+
+// Trigger sends Message from Node when an Exchange runs.
+type Trigger struct {
+	Node    *network.Network
+	Message interface{}
+}
+
+// Expect is satisfied once a message arriving on Mailbox matches Match.
+// Mailbox must be a receive-only or bidirectional channel, typically a
+// plugin's own mailbox field (e.g. `chan *messages.BasicMessage`).
+type Expect struct {
+	Label   string
+	Mailbox interface{}
+	Match   func(msg interface{}) bool
+}
+
+// Exchange describes one round of an integration test: a set of Triggers to
+// fire and a set of Expects that must all be satisfied, in any order,
+// before Timeout elapses.
+type Exchange struct {
+	Label    string
+	Triggers []Trigger
+	Expects  []Expect
+	Timeout  time.Duration
+}
+
+// Run fires every Trigger and then fans out over every pending Expect's
+// mailbox using reflect.Select, completing as soon as each Expect has been
+// satisfied at least once regardless of delivery order. It fails with a
+// diff of the expectations that never matched once Timeout elapses.
+func (e Exchange) Run() error {
+	for _, trig := range e.Triggers {
+		trig.Node.Broadcast(trig.Message)
+	}
+
+	pending := make([]Expect, len(e.Expects))
+	copy(pending, e.Expects)
+
+	deadline := time.NewTimer(e.Timeout)
+	defer deadline.Stop()
+
+	for len(pending) > 0 {
+		cases := make([]reflect.SelectCase, len(pending)+1)
+		for i, exp := range pending {
+			cases[i] = reflect.SelectCase{
+				Dir:  reflect.SelectRecv,
+				Chan: reflect.ValueOf(exp.Mailbox),
+			}
+		}
+		cases[len(pending)] = reflect.SelectCase{
+			Dir:  reflect.SelectRecv,
+			Chan: reflect.ValueOf(deadline.C),
+		}
+
+		chosen, recv, _ := reflect.Select(cases)
+		if chosen == len(pending) {
+			return errors.Errorf("exchange %q: timed out with unmet expectations: %s", e.Label, unmet(pending))
+		}
+
+		exp := pending[chosen]
+		if exp.Match(recv.Interface()) {
+			pending = append(pending[:chosen], pending[chosen+1:]...)
+		}
+	}
+
+	return nil
+}
+
+func unmet(pending []Expect) string {
+	labels := make([]string, len(pending))
+	for i, exp := range pending {
+		labels[i] = exp.Label
+	}
+	return fmt.Sprintf("%v", labels)
+}
@@ -0,0 +1,23 @@
+package network
+
+import "net"
+
+// Transport abstracts how a Network dials peers and listens for inbound
+// connections, so alternative transports (such as network/simulations'
+// InprocAdapter) can be substituted for real TCP sockets in tests.
+type Transport interface {
+	Dial(address string) (net.Conn, error)
+	Listen(address string) (net.Listener, error)
+}
+
+// tcpTransport is the default Transport, backed by real TCP sockets. It is
+// used whenever a NetworkBuilder is never given an explicit Transport.
+type tcpTransport struct{}
+
+func (tcpTransport) Dial(address string) (net.Conn, error) {
+	return net.Dial("tcp", address)
+}
+
+func (tcpTransport) Listen(address string) (net.Listener, error) {
+	return net.Listen("tcp", address)
+}
@@ -0,0 +1,29 @@
+package network
+
+import (
+	"encoding/gob"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// frame is the envelope PeerClient encodes every message in when writing to
+// the underlying connection; gob needs a concrete, registered type behind
+// the proto.Message interface to decode into, which is what frame carries.
+type frame struct {
+	Message proto.Message
+}
+
+// helloMessage is the first frame a dialing PeerClient sends, announcing the
+// address it is reachable at so the listening side can key the connection by
+// it instead of by the ephemeral address the transport happened to see.
+type helloMessage struct {
+	Address string
+}
+
+func (m *helloMessage) Reset()         { *m = helloMessage{} }
+func (m *helloMessage) String() string { return m.Address }
+func (m *helloMessage) ProtoMessage()  {}
+
+func init() {
+	gob.Register(&helloMessage{})
+}